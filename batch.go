@@ -0,0 +1,84 @@
+package omglol
+
+import (
+	"context"
+	"errors"
+
+	"github.com/libdns/libdns"
+	"golang.org/x/sync/errgroup"
+)
+
+const defaultMaxConcurrency = 4
+
+func (p *Provider) maxConcurrency() int {
+	if p.MaxConcurrency > 0 {
+		return p.MaxConcurrency
+	}
+	return defaultMaxConcurrency
+}
+
+// runBatch runs fn for each item concurrently, capped at
+// Provider.MaxConcurrency workers, and returns one result per item in input
+// order. If Provider.ContinueOnError is false (the default), the first
+// error cancels the context passed to fn for every other item and is
+// returned once all items have finished, with results for items that
+// failed or were cancelled left as their zero value. If
+// ContinueOnError is true, every item runs to completion and their errors
+// are aggregated with errors.Join.
+func runBatch[T, R any](ctx context.Context, p *Provider, items []T, fn func(ctx context.Context, item T) (R, error)) ([]R, error) {
+	results := make([]R, len(items))
+
+	if p.ContinueOnError {
+		g, gctx := errgroup.WithContext(ctx)
+		g.SetLimit(p.maxConcurrency())
+
+		errs := make([]error, len(items))
+		for i, item := range items {
+			i, item := i, item
+			g.Go(func() error {
+				result, err := fn(gctx, item)
+				if err != nil {
+					errs[i] = err
+					return nil
+				}
+				results[i] = result
+				return nil
+			})
+		}
+		_ = g.Wait()
+
+		return results, errors.Join(errs...)
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(p.maxConcurrency())
+
+	for i, item := range items {
+		i, item := i, item
+		g.Go(func() error {
+			result, err := fn(gctx, item)
+			if err != nil {
+				return err
+			}
+			results[i] = result
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// compactRecords drops nil entries (left behind by failed batch items),
+// preserving the order of the remaining records.
+func compactRecords(records []libdns.Record) []libdns.Record {
+	out := make([]libdns.Record, 0, len(records))
+	for _, r := range records {
+		if r != nil {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package omglol
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/folone/libdns-omglol/internal/omglolapi"
+)
+
+// recordCache holds a cached snapshot of a zone's DNS records and the
+// per-name locks used to serialise concurrent mutations against it.
+type recordCache struct {
+	mu        sync.Mutex
+	records   []omglolapi.Record
+	fetchedAt time.Time
+
+	keyMu sync.Mutex
+	keys  map[string]*sync.Mutex
+}
+
+// cache returns the Provider's record cache, initialising it on first use.
+func (p *Provider) cache() *recordCache {
+	p.cacheOnce.Do(func() {
+		p.cachedRecords = &recordCache{}
+	})
+	return p.cachedRecords
+}
+
+// listRecordsCached returns the cached record list when Provider.CacheTTL is
+// set and the cache hasn't expired, otherwise it fetches a fresh list from
+// the API and caches it.
+func (p *Provider) listRecordsCached(ctx context.Context) ([]omglolapi.Record, error) {
+	if p.CacheTTL <= 0 {
+		return p.listRecords(ctx)
+	}
+
+	c := p.cache()
+
+	c.mu.Lock()
+	if c.records != nil && time.Since(c.fetchedAt) < p.CacheTTL {
+		records := c.records
+		c.mu.Unlock()
+		return records, nil
+	}
+	c.mu.Unlock()
+
+	records, err := p.listRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.records = records
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+
+	return records, nil
+}
+
+// invalidateCache drops any cached record list, forcing the next read to hit
+// the API. It's called after every successful create/update/delete.
+func (p *Provider) invalidateCache() {
+	c := p.cache()
+	c.mu.Lock()
+	c.records = nil
+	c.mu.Unlock()
+}
+
+// Refresh forces the cached record list to be reloaded from the omg.lol API.
+// It's a no-op on the cache's usefulness if CacheTTL is 0, but still performs
+// the fetch so callers can use it unconditionally before reading records.
+func (p *Provider) Refresh(ctx context.Context) error {
+	records, err := p.listRecords(ctx)
+	if err != nil {
+		return err
+	}
+
+	c := p.cache()
+	c.mu.Lock()
+	c.records = records
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+
+	return nil
+}
+
+// lockRecord serialises concurrent mutations for the same (type, name)
+// tuple, so that e.g. two concurrent SetRecords calls for
+// "_acme-challenge" don't race into duplicate creates. It returns a function
+// that releases the lock.
+func (p *Provider) lockRecord(recordType, name string) func() {
+	c := p.cache()
+	key := strings.ToUpper(recordType) + "|" + strings.ToLower(name)
+
+	c.keyMu.Lock()
+	if c.keys == nil {
+		c.keys = make(map[string]*sync.Mutex)
+	}
+	mu, ok := c.keys[key]
+	if !ok {
+		mu = &sync.Mutex{}
+		c.keys[key] = mu
+	}
+	c.keyMu.Unlock()
+
+	mu.Lock()
+	return mu.Unlock
+}
@@ -1,197 +1,121 @@
 package omglol
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 	"time"
 
+	"github.com/folone/libdns-omglol/internal/omglolapi"
 	"github.com/libdns/libdns"
 )
 
-const apiBase = "https://api.omg.lol"
+// client lazily builds the internal/omglolapi.Client for this Provider,
+// translating the user-facing Provider config fields into it.
+func (p *Provider) client() *omglolapi.Client {
+	p.apiClientOnce.Do(func() {
+		p.apiClient = &omglolapi.Client{
+			APIKey:               p.APIKey,
+			Address:              p.Address,
+			HTTPClient:           p.HTTPClient,
+			MaxRetries:           p.MaxRetries,
+			RetryInitialInterval: p.RetryInitialInterval,
+			RetryMaxInterval:     p.RetryMaxInterval,
+		}
+	})
+	return p.apiClient
+}
 
 // listRecords fetches all DNS records for the configured address.
-func (p *Provider) listRecords(ctx context.Context) ([]omglolRecord, error) {
-	url := fmt.Sprintf("%s/address/%s/dns", apiBase, p.Address)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", "Bearer "+p.APIKey)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("omg.lol API: HTTP %d: %s", resp.StatusCode, string(body))
-	}
-
-	var result omglolListResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("omg.lol API: failed to parse response: %w", err)
-	}
-
-	if !result.Request.Success {
-		return nil, fmt.Errorf("omg.lol API: request unsuccessful")
-	}
-
-	return result.Response.DNS, nil
+func (p *Provider) listRecords(ctx context.Context) ([]omglolapi.Record, error) {
+	return p.client().ListRecords(ctx)
 }
 
 // createRecord creates a new DNS record and returns the created record (with ID).
-func (p *Provider) createRecord(ctx context.Context, payload omglolRecordPayload) (omglolRecord, error) {
-	url := fmt.Sprintf("%s/address/%s/dns", apiBase, p.Address)
-
-	data, err := json.Marshal(payload)
-	if err != nil {
-		return omglolRecord{}, err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
-	if err != nil {
-		return omglolRecord{}, err
-	}
-	req.Header.Set("Authorization", "Bearer "+p.APIKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return omglolRecord{}, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return omglolRecord{}, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return omglolRecord{}, fmt.Errorf("omg.lol API: HTTP %d: %s", resp.StatusCode, string(body))
-	}
-
-	var result omglolCreateResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return omglolRecord{}, fmt.Errorf("omg.lol API: failed to parse create response: %w", err)
-	}
-
-	if !result.Request.Success {
-		return omglolRecord{}, fmt.Errorf("omg.lol API: create unsuccessful")
-	}
-
-	return result.Response.ResponseReceived.Data, nil
+func (p *Provider) createRecord(ctx context.Context, payload omglolapi.RecordPayload) (omglolapi.Record, error) {
+	return p.client().CreateRecord(ctx, payload)
 }
 
 // updateRecord updates an existing DNS record by its ID.
-func (p *Provider) updateRecord(ctx context.Context, id string, payload omglolRecordPayload) error {
-	url := fmt.Sprintf("%s/address/%s/dns/%s", apiBase, p.Address, id)
-
-	data, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url, bytes.NewReader(data))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", "Bearer "+p.APIKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("omg.lol API: HTTP %d: %s", resp.StatusCode, string(body))
-	}
-
-	return nil
+func (p *Provider) updateRecord(ctx context.Context, id string, payload omglolapi.RecordPayload) error {
+	return p.client().UpdateRecord(ctx, id, payload)
 }
 
 // deleteRecord deletes a DNS record by its ID.
 func (p *Provider) deleteRecord(ctx context.Context, id string) error {
-	url := fmt.Sprintf("%s/address/%s/dns/%s", apiBase, p.Address, id)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", "Bearer "+p.APIKey)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("omg.lol API: HTTP %d: %s", resp.StatusCode, string(body))
-	}
-
-	return nil
+	return p.client().DeleteRecord(ctx, id)
 }
 
 // libdnsRecordToPayload converts a libdns.Record into the payload used by the
 // omg.lol create/update endpoints. The name is converted from the libdns
 // relative/absolute representation into what omg.lol expects: just the label
 // prefix (e.g. "_acme-challenge" for "_acme-challenge.g.omg.lol.").
-func libdnsRecordToPayload(record libdns.Record, zone string) omglolRecordPayload {
+//
+// It type-switches on the concrete record type so that fields which don't
+// fit into the generic libdns.RR (MX preference, SRV weight/port, CAA
+// flags/tag, SVCB priority) are serialised into Data/Priority the way the
+// omg.lol API expects, instead of being dropped.
+func libdnsRecordToPayload(record libdns.Record, zone string) omglolapi.RecordPayload {
 	rr := record.RR()
 	ttl := int(rr.TTL / time.Second)
 	if ttl <= 0 {
 		ttl = 300
 	}
 
-	// Extract the address label from the zone (e.g. "g" from "g.omg.lol.").
-	parts := strings.SplitN(strings.TrimSuffix(zone, "."), ".", 2)
-	address := parts[0]
+	address := zoneAddress(zone)
+
+	payload := omglolapi.RecordPayload{
+		Type: rr.Type,
+		Name: omglolName(rr.Name, zone, address),
+		Data: rr.Data,
+		TTL:  ttl,
+	}
+
+	switch rec := record.(type) {
+	case libdns.MX:
+		priority := int(rec.Preference)
+		payload.Priority = &priority
+		payload.Data = rec.Target
+	case libdns.SRV:
+		priority := int(rec.Priority)
+		payload.Priority = &priority
+		label := fmt.Sprintf("_%s._%s", rec.Service, rec.Transport)
+		fullName := label
+		if rec.Name != "" && rec.Name != "@" {
+			fullName = label + "." + rec.Name
+		}
+		payload.Name = omglolName(fullName, zone, address)
+		payload.Data = fmt.Sprintf("%d %d %s", rec.Weight, rec.Port, rec.Target)
+	case libdns.CAA:
+		payload.Data = fmt.Sprintf("%d %s %q", rec.Flags, rec.Tag, rec.Value)
+	case libdns.ServiceBinding:
+		priority := int(rec.Priority)
+		payload.Priority = &priority
+		payload.Data = rec.Target
+		if paramsStr := formatSVCBParams(rec.Params); paramsStr != "" {
+			payload.Data = rec.Target + " " + paramsStr
+		}
+	}
+
+	return payload
+}
 
-	// libdns names are relative to the zone or absolute (FQDN).
-	// omg.lol expects just the sub-label portion relative to the address label.
-	// For zone "g.omg.lol." the address is "g".
-	// "_acme-challenge" relative to "g.omg.lol." → omg.lol name = "_acme-challenge"
-	// "@" or "" (apex) → omg.lol name = address itself (e.g. "g")
-	relativeName := libdns.RelativeName(rr.Name, zone)
+// zoneAddress extracts the omg.lol address label from a zone, e.g. "g" from
+// "g.omg.lol.".
+func zoneAddress(zone string) string {
+	parts := strings.SplitN(strings.TrimSuffix(zone, "."), ".", 2)
+	return parts[0]
+}
 
-	var omglolName string
+// omglolName converts a libdns record name (relative or absolute) into the
+// label omg.lol expects: just the sub-label portion, or the address itself
+// for the apex record.
+func omglolName(name, zone, address string) string {
+	relativeName := libdns.RelativeName(name, zone)
 	switch relativeName {
 	case "@", "":
-		omglolName = address
+		return address
 	default:
-		// relativeName is e.g. "_acme-challenge" or "_acme-challenge.sub"
-		omglolName = relativeName
-	}
-
-	return omglolRecordPayload{
-		Type: rr.Type,
-		Name: omglolName,
-		Data: rr.Data,
-		TTL:  ttl,
+		return relativeName
 	}
 }
@@ -0,0 +1,10 @@
+package omglol
+
+import "github.com/folone/libdns-omglol/internal/omglolapi"
+
+// APIError represents a response from the omg.lol API whose status is not
+// 200 OK. Callers can
+// use errors.As(err, new(*omglol.APIError)) to distinguish auth failures,
+// missing records, and rate limiting instead of matching on formatted error
+// strings.
+type APIError = omglolapi.APIError
@@ -0,0 +1,287 @@
+package omglolapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const apiBase = "https://api.omg.lol"
+
+// moduleVersion is reported in the User-Agent header sent with every request.
+const moduleVersion = "0.1.0"
+
+const userAgent = "libdns-omglol/" + moduleVersion + " (+libdns)"
+
+const (
+	defaultMaxRetries           = 3
+	defaultRetryInitialInterval = 500 * time.Millisecond
+	defaultRetryMaxInterval     = 10 * time.Second
+)
+
+// Client is a minimal HTTP client for the omg.lol DNS API.
+type Client struct {
+	// APIKey is the omg.lol API key used for authentication.
+	APIKey string
+
+	// Address is the omg.lol address/handle that owns the zone.
+	Address string
+
+	// HTTPClient is the client used for requests. If nil, http.DefaultClient
+	// is used.
+	HTTPClient *http.Client
+
+	// MaxRetries is the maximum number of retry attempts for requests that
+	// fail with a network error or an HTTP 429/5xx response. Defaults to 3.
+	MaxRetries int
+
+	// RetryInitialInterval is the base delay before the first retry, doubling
+	// (with jitter) on each subsequent attempt up to RetryMaxInterval.
+	// Defaults to 500ms.
+	RetryInitialInterval time.Duration
+
+	// RetryMaxInterval caps the backoff delay between retries. Defaults to 10s.
+	RetryMaxInterval time.Duration
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) maxRetries() int {
+	if c.MaxRetries > 0 {
+		return c.MaxRetries
+	}
+	return defaultMaxRetries
+}
+
+func (c *Client) retryInitialInterval() time.Duration {
+	if c.RetryInitialInterval > 0 {
+		return c.RetryInitialInterval
+	}
+	return defaultRetryInitialInterval
+}
+
+func (c *Client) retryMaxInterval() time.Duration {
+	if c.RetryMaxInterval > 0 {
+		return c.RetryMaxInterval
+	}
+	return defaultRetryMaxInterval
+}
+
+// doRequest performs an HTTP request against the omg.lol API and returns the
+// response body and status code. It retries network errors with exponential
+// backoff and jitter, up to MaxRetries times; when retryOnServerError is
+// true it also retries 429/5xx responses, honouring a Retry-After response
+// header when present.
+//
+// retryOnServerError must be false for non-idempotent requests (record
+// creation): a 5xx/429 response doesn't tell us whether the omg.lol API
+// applied the request before failing, so retrying on status would risk
+// creating the same record twice. A network error before any response is
+// received is safe to retry regardless, since the request never reached the
+// API.
+func (c *Client) doRequest(ctx context.Context, method, url string, body []byte, retryOnServerError bool) ([]byte, int, error) {
+	var lastErr error
+
+	for attempt := 0; ; attempt++ {
+		var bodyReader io.Reader
+		if body != nil {
+			bodyReader = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+		if err != nil {
+			return nil, 0, err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+		req.Header.Set("User-Agent", userAgent)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.httpClient().Do(req)
+		if err != nil {
+			lastErr = err
+			if attempt >= c.maxRetries() {
+				return nil, 0, fmt.Errorf("omg.lol API: request failed: %w", lastErr)
+			}
+			if !c.sleepBackoff(ctx, attempt, 0) {
+				return nil, 0, fmt.Errorf("omg.lol API: request failed: %w", ctx.Err())
+			}
+			continue
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, resp.StatusCode, fmt.Errorf("omg.lol API: failed to read response: %w", err)
+		}
+
+		if retryOnServerError && isRetryableStatus(resp.StatusCode) && attempt < c.maxRetries() {
+			retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+			if !c.sleepBackoff(ctx, attempt, retryAfter) {
+				return respBody, resp.StatusCode, ctx.Err()
+			}
+			continue
+		}
+
+		return respBody, resp.StatusCode, nil
+	}
+}
+
+// sleepBackoff waits for either retryAfter (if positive) or an exponential
+// backoff with jitter, returning false if ctx was cancelled first.
+func (c *Client) sleepBackoff(ctx context.Context, attempt int, retryAfter time.Duration) bool {
+	delay := retryAfter
+	if delay <= 0 {
+		delay = backoffDelay(attempt, c.retryInitialInterval(), c.retryMaxInterval())
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// backoffDelay computes an exponential backoff delay for the given attempt
+// (0-indexed), capped at max and jittered by up to half its value so that
+// concurrent callers don't retry in lockstep.
+func backoffDelay(attempt int, initial, max time.Duration) time.Duration {
+	delay := initial << attempt
+	if delay <= 0 || delay > max {
+		delay = max
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	return delay/2 + jitter
+}
+
+// isRetryableStatus reports whether an HTTP status code warrants a retry.
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// parseRetryAfter parses a Retry-After header, which may be either a number
+// of seconds or an HTTP date. It returns 0 if the header is absent or
+// unparseable.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// ListRecords fetches all DNS records for the configured address.
+func (c *Client) ListRecords(ctx context.Context) ([]Record, error) {
+	url := fmt.Sprintf("%s/address/%s/dns", apiBase, c.Address)
+
+	body, status, err := c.doRequest(ctx, http.MethodGet, url, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return nil, &APIError{StatusCode: status, Message: string(body), Raw: body}
+	}
+
+	var result ListResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("omg.lol API: failed to parse response: %w", err)
+	}
+
+	if !result.Request.Success {
+		return nil, fmt.Errorf("omg.lol API: request unsuccessful")
+	}
+
+	return result.Response.DNS, nil
+}
+
+// CreateRecord creates a new DNS record and returns the created record (with
+// ID). Unlike the other methods, a 429/5xx response here is not retried: the
+// omg.lol API may have applied the create before failing to respond, and
+// resending the same payload would risk creating a duplicate record.
+func (c *Client) CreateRecord(ctx context.Context, payload RecordPayload) (Record, error) {
+	url := fmt.Sprintf("%s/address/%s/dns", apiBase, c.Address)
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Record{}, err
+	}
+
+	body, status, err := c.doRequest(ctx, http.MethodPost, url, data, false)
+	if err != nil {
+		return Record{}, err
+	}
+
+	if status != http.StatusOK {
+		return Record{}, &APIError{StatusCode: status, Message: string(body), Raw: body}
+	}
+
+	var result CreateResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return Record{}, fmt.Errorf("omg.lol API: failed to parse create response: %w", err)
+	}
+
+	if !result.Request.Success {
+		return Record{}, fmt.Errorf("omg.lol API: create unsuccessful")
+	}
+
+	return result.Response.ResponseReceived.Data, nil
+}
+
+// UpdateRecord updates an existing DNS record by its ID.
+func (c *Client) UpdateRecord(ctx context.Context, id string, payload RecordPayload) error {
+	url := fmt.Sprintf("%s/address/%s/dns/%s", apiBase, c.Address, id)
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	body, status, err := c.doRequest(ctx, http.MethodPatch, url, data, true)
+	if err != nil {
+		return err
+	}
+
+	if status != http.StatusOK {
+		return &APIError{StatusCode: status, Message: string(body), Raw: body}
+	}
+
+	return nil
+}
+
+// DeleteRecord deletes a DNS record by its ID.
+func (c *Client) DeleteRecord(ctx context.Context, id string) error {
+	url := fmt.Sprintf("%s/address/%s/dns/%s", apiBase, c.Address, id)
+
+	body, status, err := c.doRequest(ctx, http.MethodDelete, url, nil, true)
+	if err != nil {
+		return err
+	}
+
+	if status != http.StatusOK {
+		return &APIError{StatusCode: status, Message: string(body), Raw: body}
+	}
+
+	return nil
+}
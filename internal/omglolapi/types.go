@@ -0,0 +1,115 @@
+// Package omglolapi implements a minimal HTTP client for the omg.lol DNS
+// API, independent of libdns. The omglol package wraps it to satisfy the
+// libdns interfaces.
+package omglolapi
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Record represents a DNS record as returned by the omg.lol API.
+type Record struct {
+	ID       interface{} `json:"id"`
+	Type     string      `json:"type"`
+	Name     string      `json:"name"`
+	Data     string      `json:"data"`
+	Priority *int        `json:"priority,omitempty"`
+	TTL      interface{} `json:"ttl"`
+}
+
+// RecordID extracts the record ID as a string regardless of whether the API
+// returned it as a number or a string.
+func (r Record) RecordID() string {
+	switch v := r.ID.(type) {
+	case float64:
+		return strconv.FormatInt(int64(v), 10)
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// TTLSeconds returns the TTL as an integer number of seconds.
+func (r Record) TTLSeconds() int {
+	switch v := r.TTL.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 3600
+		}
+		return n
+	default:
+		return 3600
+	}
+}
+
+// RecordPayload is the JSON body for create/update requests.
+type RecordPayload struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Data     string `json:"data"`
+	TTL      int    `json:"ttl"`
+	Priority *int   `json:"priority,omitempty"`
+}
+
+// ListResponse is the top-level response for GET /address/{address}/dns.
+type ListResponse struct {
+	Request struct {
+		StatusCode int  `json:"status_code"`
+		Success    bool `json:"success"`
+	} `json:"request"`
+	Response struct {
+		Message string   `json:"message"`
+		DNS     []Record `json:"dns"`
+	} `json:"response"`
+}
+
+// CreateResponse is the response for POST /address/{address}/dns.
+type CreateResponse struct {
+	Request struct {
+		StatusCode int  `json:"status_code"`
+		Success    bool `json:"success"`
+	} `json:"request"`
+	Response struct {
+		Message          string `json:"message"`
+		ResponseReceived struct {
+			Data Record `json:"data"`
+		} `json:"response_received"`
+	} `json:"response"`
+}
+
+// APIError represents a response from the omg.lol API whose status is not
+// 200 OK. Callers can
+// use errors.As to distinguish auth failures, missing records, and rate
+// limiting instead of matching on formatted error strings.
+type APIError struct {
+	StatusCode int
+	Message    string
+	Raw        []byte
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("omg.lol API: HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// IsAuthFailure reports whether the error is a 401 Unauthorized response.
+func (e *APIError) IsAuthFailure() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+// IsNotFound reports whether the error is a 404 Not Found response.
+func (e *APIError) IsNotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// IsRateLimited reports whether the error is a 429 Too Many Requests response.
+func (e *APIError) IsRateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
@@ -1,105 +1,213 @@
 package omglol
 
 import (
-	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/folone/libdns-omglol/internal/omglolapi"
 	"github.com/libdns/libdns"
 )
 
-// omglolRecord represents a DNS record as returned by the omg.lol API.
-type omglolRecord struct {
-	ID       interface{} `json:"id"`
-	Type     string      `json:"type"`
-	Name     string      `json:"name"`
-	Data     string      `json:"data"`
-	Priority *int        `json:"priority,omitempty"`
-	TTL      interface{} `json:"ttl"`
-}
+// recordToLibdns converts an omg.lol API record into a libdns.Record. It
+// returns the most specific typed record available for r.Type (libdns.MX,
+// libdns.SRV, libdns.CAA, libdns.ServiceBinding) so that fields such as MX
+// priority or SRV weight/port survive the round trip instead of being
+// flattened into a raw libdns.RR. The zone is the FQDN with trailing dot
+// (e.g. "g.omg.lol.").
+func recordToLibdns(r omglolapi.Record, zone string) libdns.Record {
+	ttl := time.Duration(r.TTLSeconds()) * time.Second
+	name := relativeRecordName(r.Name, zone)
 
-// omglolListResponse is the top-level response for GET /address/{address}/dns.
-type omglolListResponse struct {
-	Request struct {
-		StatusCode int  `json:"status_code"`
-		Success    bool `json:"success"`
-	} `json:"request"`
-	Response struct {
-		Message string         `json:"message"`
-		DNS     []omglolRecord `json:"dns"`
-	} `json:"response"`
+	switch strings.ToUpper(r.Type) {
+	case "MX":
+		preference, target := parseMXData(r.Data, r.Priority)
+		return libdns.MX{
+			Name:       name,
+			TTL:        ttl,
+			Preference: preference,
+			Target:     target,
+		}
+	case "SRV":
+		service, transport, base := parseSRVName(name)
+		priority, weight, port, target := parseSRVData(r.Data, r.Priority)
+		return libdns.SRV{
+			Service:   service,
+			Transport: transport,
+			Name:      base,
+			TTL:       ttl,
+			Priority:  priority,
+			Weight:    weight,
+			Port:      port,
+			Target:    target,
+		}
+	case "CAA":
+		flags, tag, value := parseCAAData(r.Data)
+		return libdns.CAA{
+			Name:  name,
+			TTL:   ttl,
+			Flags: flags,
+			Tag:   tag,
+			Value: value,
+		}
+	case "SVCB", "HTTPS":
+		priority, target, params := parseSVCBData(r.Data, r.Priority)
+		return libdns.ServiceBinding{
+			Scheme:   strings.ToLower(r.Type),
+			Name:     name,
+			TTL:      ttl,
+			Priority: priority,
+			Target:   target,
+			Params:   params,
+		}
+	default:
+		return libdns.RR{
+			Name: name,
+			TTL:  ttl,
+			Type: r.Type,
+			Data: r.Data,
+		}
+	}
 }
 
-// omglolCreateResponse is the response for POST /address/{address}/dns.
-type omglolCreateResponse struct {
-	Request struct {
-		StatusCode int  `json:"status_code"`
-		Success    bool `json:"success"`
-	} `json:"request"`
-	Response struct {
-		Message          string `json:"message"`
-		ResponseReceived struct {
-			Data omglolRecord `json:"data"`
-		} `json:"response_received"`
-	} `json:"response"`
+// relativeRecordName converts an omg.lol record name (e.g. "g" or
+// "_acme-challenge.g") into a name relative to zone, as libdns expects.
+func relativeRecordName(omglolName, zone string) string {
+	// omglolName already ends in the address label (the zone's first label),
+	// so only the zone's parent domain is appended to form the FQDN.
+	_, parent, _ := strings.Cut(strings.TrimSuffix(zone, "."), ".")
+	name := libdns.RelativeName(omglolName+"."+parent+".", zone)
+	if name == "" {
+		name = "@"
+	}
+	return name
 }
 
-// omglolRecordPayload is the JSON body for create/update requests.
-type omglolRecordPayload struct {
-	Type string `json:"type"`
-	Name string `json:"name"`
-	Data string `json:"data"`
-	TTL  int    `json:"ttl"`
+// parseMXData extracts the MX preference and target, preferring the
+// dedicated priority field returned by the API and falling back to parsing
+// it out of Data (e.g. "10 mail.example.com") for older responses that only
+// populate Data.
+func parseMXData(data string, priority *int) (preference uint16, target string) {
+	if priority != nil {
+		return uint16(*priority), strings.TrimSpace(data)
+	}
+	if fields := strings.Fields(data); len(fields) == 2 {
+		if p, err := strconv.Atoi(fields[0]); err == nil {
+			return uint16(p), fields[1]
+		}
+	}
+	return 0, strings.TrimSpace(data)
 }
 
-// recordID extracts the record ID as a string regardless of whether the API
-// returned it as a number or a string.
-func (r omglolRecord) recordID() string {
-	switch v := r.ID.(type) {
-	case float64:
-		return strconv.FormatInt(int64(v), 10)
-	case string:
-		return v
+// parseSRVName splits a record name of the form "_service._proto.base" into
+// its components. A name with no base label ("_service._proto") is an apex
+// SRV record, returned with base "@". Names that don't follow the SRV
+// convention are returned unchanged as the base name.
+func parseSRVName(name string) (service, transport, base string) {
+	labels := strings.SplitN(name, ".", 3)
+	switch {
+	case len(labels) == 3 && strings.HasPrefix(labels[0], "_") && strings.HasPrefix(labels[1], "_"):
+		return strings.TrimPrefix(labels[0], "_"), strings.TrimPrefix(labels[1], "_"), labels[2]
+	case len(labels) == 2 && strings.HasPrefix(labels[0], "_") && strings.HasPrefix(labels[1], "_"):
+		return strings.TrimPrefix(labels[0], "_"), strings.TrimPrefix(labels[1], "_"), "@"
 	default:
-		return fmt.Sprintf("%v", v)
+		return "", "", name
 	}
 }
 
-// ttlSeconds returns the TTL as an integer number of seconds.
-func (r omglolRecord) ttlSeconds() int {
-	switch v := r.TTL.(type) {
-	case float64:
-		return int(v)
-	case string:
-		n, err := strconv.Atoi(v)
-		if err != nil {
-			return 3600
-		}
-		return n
+// parseSRVData extracts SRV priority/weight/port/target from Data, which
+// omg.lol represents as "<weight> <port> <target>" alongside the dedicated
+// priority field, or "<priority> <weight> <port> <target>" when priority is
+// embedded.
+func parseSRVData(data string, priority *int) (prio, weight, port uint16, target string) {
+	fields := strings.Fields(data)
+	switch len(fields) {
+	case 4:
+		p, _ := strconv.Atoi(fields[0])
+		w, _ := strconv.Atoi(fields[1])
+		pt, _ := strconv.Atoi(fields[2])
+		return uint16(p), uint16(w), uint16(pt), fields[3]
+	case 3:
+		w, _ := strconv.Atoi(fields[0])
+		pt, _ := strconv.Atoi(fields[1])
+		return priorityOrZero(priority), uint16(w), uint16(pt), fields[2]
 	default:
-		return 3600
+		return priorityOrZero(priority), 0, 0, strings.TrimSpace(data)
+	}
+}
+
+// parseCAAData splits CAA Data of the form `<flags> <tag> "<value>"` into
+// its components.
+func parseCAAData(data string) (flags uint8, tag, value string) {
+	fields := strings.SplitN(strings.TrimSpace(data), " ", 3)
+	if len(fields) == 3 {
+		f, _ := strconv.Atoi(fields[0])
+		return uint8(f), fields[1], strings.Trim(fields[2], `"`)
 	}
+	return 0, "", data
 }
 
-// toLibdnsRecord converts an omg.lol DNS record into a libdns.Record.
-// The zone is the FQDN with trailing dot (e.g. "g.omg.lol.").
-func (r omglolRecord) toLibdnsRecord(zone string) libdns.Record {
-	ttl := time.Duration(r.ttlSeconds()) * time.Second
+// parseSVCBData extracts the target and params from SVCB/HTTPS Data, which
+// omg.lol represents in RFC 9460 presentation format ("<target>
+// [key=val,val2 ...]") alongside the dedicated priority field.
+func parseSVCBData(data string, priority *int) (prio uint16, target string, params libdns.SvcParams) {
+	fields := strings.Fields(data)
+	if len(fields) > 0 {
+		target = fields[0]
+	}
+	if len(fields) > 1 {
+		params = parseSVCBParams(fields[1:])
+	}
+	return priorityOrZero(priority), target, params
+}
 
-	// omg.lol returns the full name including the address label, e.g.
-	// "g" or "_acme-challenge.g".  libdns wants a name relative to the zone.
-	name := libdns.RelativeName(r.Name+"."+strings.TrimSuffix(zone, "."), zone)
-	if name == "" {
-		name = "@"
+// parseSVCBParams parses SVCB/HTTPS params out of their RFC 9460
+// presentation format, e.g. "alpn=h2,h3" or a valueless flag like "no-default-alpn".
+func parseSVCBParams(fields []string) libdns.SvcParams {
+	if len(fields) == 0 {
+		return nil
+	}
+	params := make(libdns.SvcParams, len(fields))
+	for _, f := range fields {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok {
+			params[key] = nil
+			continue
+		}
+		params[key] = strings.Split(value, ",")
+	}
+	return params
+}
+
+// formatSVCBParams serialises SVCB/HTTPS params into RFC 9460 presentation
+// format, e.g. "alpn=h2,h3 port=8003", with keys sorted for determinism.
+func formatSVCBParams(params libdns.SvcParams) string {
+	if len(params) == 0 {
+		return ""
 	}
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 
-	rr := libdns.RR{
-		Name: name,
-		TTL:  ttl,
-		Type: r.Type,
-		Data: r.Data,
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values := params[k]
+		if len(values) == 0 {
+			parts = append(parts, k)
+			continue
+		}
+		parts = append(parts, k+"="+strings.Join(values, ","))
 	}
+	return strings.Join(parts, " ")
+}
 
-	return libdns.Record(rr)
+// priorityOrZero dereferences an optional priority, defaulting to 0 when absent.
+func priorityOrZero(priority *int) uint16 {
+	if priority == nil {
+		return 0
+	}
+	return uint16(*priority)
 }
@@ -0,0 +1,236 @@
+package omglol
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+const (
+	defaultPropagationInitialDelay = 5 * time.Second
+	defaultPropagationInterval     = 5 * time.Second
+	defaultPropagationQueryTimeout = 5 * time.Second
+)
+
+// PropagationOptions configures Provider.WaitForPropagation.
+type PropagationOptions struct {
+	// Nameservers to query directly, e.g. "ns1.example.com:53". If empty,
+	// the zone's authoritative nameservers are discovered via net.LookupNS.
+	Nameservers []string
+
+	// InitialDelay is how long to wait before the first propagation check,
+	// giving the omg.lol API time to push the change out. Defaults to 5s.
+	InitialDelay time.Duration
+
+	// Interval is how long to wait between polling attempts. Defaults to 5s.
+	Interval time.Duration
+
+	// QueryTimeout bounds each individual nameserver query. Defaults to 5s.
+	QueryTimeout time.Duration
+
+	// Quorum is the minimum number of nameservers that must report the
+	// expected value before propagation is considered complete. 0 (the
+	// default) requires all nameservers to agree.
+	Quorum int
+}
+
+// WaitForPropagation polls records' zone's authoritative nameservers until
+// every TXT/A/CNAME record in records is visible with its expected value on
+// enough of them to satisfy opts.Quorum, or ctx is done. This lets callers
+// confirm a change has propagated before, e.g., asking an ACME CA to
+// validate a _acme-challenge TXT record.
+func (p *Provider) WaitForPropagation(ctx context.Context, zone string, records []libdns.Record, opts PropagationOptions) error {
+	checks := propagationChecks(records, zone)
+	if len(checks) == 0 {
+		return nil
+	}
+
+	nameservers := opts.Nameservers
+	if len(nameservers) == 0 {
+		var err error
+		nameservers, err = lookupZoneNameservers(ctx, zone)
+		if err != nil {
+			return fmt.Errorf("omg.lol: discovering nameservers for %q: %w", zone, err)
+		}
+	}
+	if len(nameservers) == 0 {
+		return fmt.Errorf("omg.lol: no nameservers found for %q", zone)
+	}
+
+	initialDelay := opts.InitialDelay
+	if initialDelay <= 0 {
+		initialDelay = defaultPropagationInitialDelay
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultPropagationInterval
+	}
+	queryTimeout := opts.QueryTimeout
+	if queryTimeout <= 0 {
+		queryTimeout = defaultPropagationQueryTimeout
+	}
+	quorum := opts.Quorum
+	if quorum <= 0 || quorum > len(nameservers) {
+		quorum = len(nameservers)
+	}
+
+	if err := sleep(ctx, initialDelay); err != nil {
+		return err
+	}
+
+	for {
+		if allPropagated(ctx, nameservers, checks, queryTimeout, quorum) {
+			return nil
+		}
+
+		if err := sleep(ctx, interval); err != nil {
+			return fmt.Errorf("omg.lol: propagation check for %q timed out: %w", zone, err)
+		}
+	}
+}
+
+// sleep waits for d or returns ctx.Err() if ctx finishes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// propagationCheck is a single name/type/value triple to verify against
+// every nameserver.
+type propagationCheck struct {
+	name string
+	typ  string
+	data string
+}
+
+// propagationChecks extracts the name/type/expected-value to verify for each
+// record, skipping types WaitForPropagation doesn't know how to query
+// (anything but TXT/A/AAAA/CNAME).
+func propagationChecks(records []libdns.Record, zone string) []propagationCheck {
+	var checks []propagationCheck
+	for _, record := range records {
+		rr := record.RR()
+		switch strings.ToUpper(rr.Type) {
+		case "TXT", "A", "AAAA", "CNAME":
+			checks = append(checks, propagationCheck{
+				name: libdns.AbsoluteName(rr.Name, zone),
+				typ:  strings.ToUpper(rr.Type),
+				data: rr.Data,
+			})
+		}
+	}
+	return checks
+}
+
+// allPropagated reports whether every check is satisfied on at least quorum
+// of nameservers. Nameservers are queried concurrently so that one slow or
+// unreachable server doesn't serialise the whole poll.
+func allPropagated(ctx context.Context, nameservers []string, checks []propagationCheck, timeout time.Duration, quorum int) bool {
+	for _, check := range checks {
+		results := make(chan bool, len(nameservers))
+		for _, ns := range nameservers {
+			ns := ns
+			go func() { results <- queryHasValue(ctx, ns, check, timeout) }()
+		}
+
+		agree := 0
+		for range nameservers {
+			if <-results {
+				agree++
+			}
+		}
+		if agree < quorum {
+			return false
+		}
+	}
+	return true
+}
+
+// queryHasValue reports whether nameserver currently answers check.name with
+// check.data.
+func queryHasValue(ctx context.Context, nameserver string, check propagationCheck, timeout time.Duration) bool {
+	qctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resolver := resolverFor(nameserver)
+
+	switch check.typ {
+	case "TXT":
+		values, err := resolver.LookupTXT(qctx, check.name)
+		if err != nil {
+			return false
+		}
+		for _, v := range values {
+			if v == check.data {
+				return true
+			}
+		}
+	case "A", "AAAA":
+		network := "ip4"
+		if check.typ == "AAAA" {
+			network = "ip6"
+		}
+		want := net.ParseIP(check.data)
+		if want == nil {
+			return false
+		}
+		ips, err := resolver.LookupIP(qctx, network, check.name)
+		if err != nil {
+			return false
+		}
+		for _, ip := range ips {
+			if ip.Equal(want) {
+				return true
+			}
+		}
+	case "CNAME":
+		target, err := resolver.LookupCNAME(qctx, check.name)
+		if err != nil {
+			return false
+		}
+		return strings.EqualFold(strings.TrimSuffix(target, "."), strings.TrimSuffix(check.data, "."))
+	}
+
+	return false
+}
+
+// resolverFor returns a net.Resolver that queries nameserver directly rather
+// than going through the system resolver.
+func resolverFor(nameserver string) *net.Resolver {
+	if _, _, err := net.SplitHostPort(nameserver); err != nil {
+		nameserver = net.JoinHostPort(nameserver, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, nameserver)
+		},
+	}
+}
+
+// lookupZoneNameservers discovers the authoritative nameservers for zone via
+// the system resolver.
+func lookupZoneNameservers(ctx context.Context, zone string) ([]string, error) {
+	nsRecords, err := net.DefaultResolver.LookupNS(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	nameservers := make([]string, 0, len(nsRecords))
+	for _, ns := range nsRecords {
+		nameservers = append(nameservers, strings.TrimSuffix(ns.Host, ".")+":53")
+	}
+	return nameservers, nil
+}
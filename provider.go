@@ -4,8 +4,12 @@ package omglol
 
 import (
 	"context"
+	"net/http"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/folone/libdns-omglol/internal/omglolapi"
 	"github.com/libdns/libdns"
 )
 
@@ -20,6 +24,47 @@ type Provider struct {
 
 	// Address is your omg.lol address/handle (e.g. "yourname" for yourname.omg.lol).
 	Address string `json:"address,omitempty"`
+
+	// HTTPClient is the client used for requests to the omg.lol API. If nil,
+	// http.DefaultClient is used. Set this to plug in instrumented
+	// transports (tracing, mTLS to a proxy, etc).
+	HTTPClient *http.Client `json:"-"`
+
+	// MaxRetries is the maximum number of retry attempts for requests that
+	// fail with a network error or an HTTP 429/5xx response. Defaults to 3.
+	MaxRetries int `json:"max_retries,omitempty"`
+
+	// RetryInitialInterval is the base delay before the first retry, doubling
+	// (with jitter) on each subsequent attempt up to RetryMaxInterval.
+	// Defaults to 500ms.
+	RetryInitialInterval time.Duration `json:"retry_initial_interval,omitempty"`
+
+	// RetryMaxInterval caps the backoff delay between retries. Defaults to 10s.
+	RetryMaxInterval time.Duration `json:"retry_max_interval,omitempty"`
+
+	// CacheTTL, if positive, caches the zone's record list for this long so
+	// that repeated SetRecords/DeleteRecords calls (e.g. certmagic solving
+	// several SAN challenges back to back) don't each re-list the zone. 0
+	// disables caching.
+	CacheTTL time.Duration `json:"cache_ttl,omitempty"`
+
+	// MaxConcurrency is the maximum number of concurrent create/update/delete
+	// requests dispatched by AppendRecords/SetRecords/DeleteRecords. Defaults
+	// to 4.
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+
+	// ContinueOnError, when true, makes AppendRecords/SetRecords/DeleteRecords
+	// run every record to completion even after some fail, aggregating all
+	// failures with errors.Join. When false (the default), the first failure
+	// cancels the requests for all other records and is returned once they
+	// have finished.
+	ContinueOnError bool `json:"continue_on_error,omitempty"`
+
+	cacheOnce     sync.Once
+	cachedRecords *recordCache
+
+	apiClientOnce sync.Once
+	apiClient     *omglolapi.Client
 }
 
 // GetRecords lists all the records in the zone.
@@ -31,93 +76,137 @@ func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record
 
 	records := make([]libdns.Record, 0, len(raw))
 	for _, r := range raw {
-		records = append(records, r.toLibdnsRecord(zone))
+		records = append(records, recordToLibdns(r, zone))
 	}
 	return records, nil
 }
 
 // AppendRecords adds records to the zone. It returns the records that were added.
 func (p *Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	var created []libdns.Record
-
-	for _, record := range records {
+	results, err := runBatch(ctx, p, records, func(ctx context.Context, record libdns.Record) (libdns.Record, error) {
+		rr := record.RR()
 		payload := libdnsRecordToPayload(record, zone)
 
+		unlock := p.lockRecord(rr.Type, payload.Name)
+		defer unlock()
+
 		result, err := p.createRecord(ctx, payload)
 		if err != nil {
-			return created, err
+			return nil, err
 		}
+		p.invalidateCache()
 
-		created = append(created, result.toLibdnsRecord(zone))
-	}
+		return recordToLibdns(result, zone), nil
+	})
 
-	return created, nil
+	return compactRecords(results), err
 }
 
 // SetRecords sets the records in the zone, either by updating existing records
 // or creating new ones.  It returns the updated records.
 func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	existing, err := p.listRecords(ctx)
+	existing, err := p.listRecordsCached(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var results []libdns.Record
-
-	for _, record := range records {
+	results, err := runBatch(ctx, p, records, func(ctx context.Context, record libdns.Record) (libdns.Record, error) {
 		rr := record.RR()
 		payload := libdnsRecordToPayload(record, zone)
 
-		// Look for an existing record with the same type and name.
-		var matched *omglolRecord
-		for i := range existing {
-			e := &existing[i]
-			if strings.EqualFold(e.Type, rr.Type) && namesMatch(e.Name, payload.Name, zone) {
-				matched = e
-				break
-			}
-		}
+		unlock := p.lockRecord(rr.Type, payload.Name)
+		defer unlock()
 
-		if matched != nil {
-			id := matched.recordID()
-			if err := p.updateRecord(ctx, id, payload); err != nil {
-				return results, err
-			}
-			// Reflect updated values back as a libdns record.
-			updated := omglolRecord{
-				ID:   matched.ID,
-				Type: payload.Type,
-				Name: payload.Name,
-				Data: payload.Data,
-				TTL:  payload.TTL,
-			}
-			results = append(results, updated.toLibdnsRecord(zone))
-		} else {
-			created, err := p.createRecord(ctx, payload)
+		// existing was read before any lock was acquired, so it can't see a
+		// record created by another SetRecords call for the same
+		// (type, name) that ran and released the lock just before us. If it
+		// shows no match we can't trust that yet — re-read under the lock
+		// before deciding to create, otherwise two concurrent calls for
+		// "_acme-challenge" both take the create branch and duplicate it.
+		current := existing
+		if findMatchingRecord(current, payload, zone) == nil {
+			fresh, err := p.listRecordsCached(ctx)
 			if err != nil {
-				return results, err
+				return nil, err
 			}
-			results = append(results, created.toLibdnsRecord(zone))
+			current = fresh
+		}
+
+		result, err := p.setRecord(ctx, zone, payload, current)
+		if err != nil {
+			return nil, err
+		}
+		p.invalidateCache()
+
+		return result, nil
+	})
+
+	return compactRecords(results), err
+}
+
+// setRecord updates the existing record matching payload's type/name, or
+// creates a new one if none matches. Unless existing already holds a match,
+// it must have been read while holding the per-(type,name) lock for
+// payload, since a concurrent SetRecords call for the same name may have
+// just created the record we'd otherwise duplicate — see lockRecord.
+func (p *Provider) setRecord(ctx context.Context, zone string, payload omglolapi.RecordPayload, existing []omglolapi.Record) (libdns.Record, error) {
+	if matched := findMatchingRecord(existing, payload, zone); matched != nil {
+		return p.updateMatchedRecord(ctx, zone, payload, matched)
+	}
+
+	created, err := p.createRecord(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	return recordToLibdns(created, zone), nil
+}
+
+// findMatchingRecord returns the existing record with the same type and
+// name as payload, or nil if there is none.
+func findMatchingRecord(existing []omglolapi.Record, payload omglolapi.RecordPayload, zone string) *omglolapi.Record {
+	for i := range existing {
+		e := &existing[i]
+		if strings.EqualFold(e.Type, payload.Type) && namesMatch(e.Name, payload.Name, zone) {
+			return e
 		}
 	}
+	return nil
+}
 
-	return results, nil
+// updateMatchedRecord updates matched in place with payload's values.
+func (p *Provider) updateMatchedRecord(ctx context.Context, zone string, payload omglolapi.RecordPayload, matched *omglolapi.Record) (libdns.Record, error) {
+	id := matched.RecordID()
+	if err := p.updateRecord(ctx, id, payload); err != nil {
+		return nil, err
+	}
+	// Reflect updated values back as a libdns record.
+	updated := omglolapi.Record{
+		ID:       matched.ID,
+		Type:     payload.Type,
+		Name:     payload.Name,
+		Data:     payload.Data,
+		Priority: payload.Priority,
+		TTL:      payload.TTL,
+	}
+	return recordToLibdns(updated, zone), nil
 }
 
 // DeleteRecords deletes the records from the zone.  It returns the records
 // that were deleted.
 func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	existing, err := p.listRecords(ctx)
+	existing, err := p.listRecordsCached(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var deleted []libdns.Record
-
-	for _, record := range records {
+	results, err := runBatch(ctx, p, records, func(ctx context.Context, record libdns.Record) ([]libdns.Record, error) {
 		rr := record.RR()
 		payload := libdnsRecordToPayload(record, zone)
 
+		unlock := p.lockRecord(rr.Type, payload.Name)
+		defer unlock()
+
+		var deleted []libdns.Record
 		for _, e := range existing {
 			if !strings.EqualFold(e.Type, rr.Type) {
 				continue
@@ -130,15 +219,21 @@ func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []lib
 				continue
 			}
 
-			id := e.recordID()
+			id := e.RecordID()
 			if err := p.deleteRecord(ctx, id); err != nil {
 				return deleted, err
 			}
-			deleted = append(deleted, e.toLibdnsRecord(zone))
+			p.invalidateCache()
+			deleted = append(deleted, recordToLibdns(e, zone))
 		}
-	}
+		return deleted, nil
+	})
 
-	return deleted, nil
+	var flattened []libdns.Record
+	for _, group := range results {
+		flattened = append(flattened, group...)
+	}
+	return flattened, err
 }
 
 // namesMatch compares the name returned by the omg.lol API (e.g. "_acme-challenge.g")